@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// loadArchive reads config.ArchivePath - a local file or an http(s) URL -
+// and converts it into Things. Two shapes are understood: Reddit's GDPR
+// data-export CSVs (comments.csv / posts.csv) and a Pushshift-style JSON
+// dump. The format is detected from the content, not the extension, since
+// a URL may not carry one. ctx governs the fetch when path is a URL, so a
+// cancelled run doesn't block shutdown on a slow or unreachable archive
+// host.
+func loadArchive(ctx context.Context, path string) ([]Thing, error) {
+	data, err := readArchiveSource(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %q: %v", path, err)
+	}
+
+	if looksLikeJSON(data) {
+		things, err := parsePushshiftArchive(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse archive %q as a pushshift dump: %v", path, err)
+		}
+		return things, nil
+	}
+
+	things, err := parseCSVArchive(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archive %q as a GDPR export CSV: %v", path, err)
+	}
+	return things, nil
+}
+
+func readArchiveSource(ctx context.Context, path string) ([]byte, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		req, err := http.NewRequestWithContext(ctx, "GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(resp.Body)
+	}
+	return os.ReadFile(path)
+}
+
+func looksLikeJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// gdprDateLayouts are the "date" column formats seen in Reddit GDPR export
+// CSVs, tried in order. The export itself uses a space-separated timestamp
+// with a literal "UTC" suffix rather than RFC3339; RFC3339 is kept as a
+// fallback in case Reddit changes the format.
+var gdprDateLayouts = []string{
+	"2006-01-02 15:04:05 UTC",
+	time.RFC3339,
+}
+
+func parseGDPRDate(value string) (time.Time, bool) {
+	for _, layout := range gdprDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseCSVArchive handles Reddit's GDPR export CSVs. comments.csv and
+// posts.csv share enough columns (id, permalink, date, subreddit) that a
+// single header-driven parser covers both; score isn't part of the export,
+// so it's left at zero and ShouldSkip's max-score filter never excludes an
+// archived item on that basis.
+func parseCSVArchive(data []byte) ([]Thing, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	col := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	get := func(row []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	var things []Thing
+	for _, row := range rows[1:] {
+		id := get(row, "id")
+		if id == "" {
+			continue
+		}
+
+		c := &Comment{
+			ID:        id,
+			Permalink: get(row, "permalink"),
+			Subreddit: get(row, "subreddit"),
+		}
+		if body := get(row, "body"); body != "" {
+			c.Body = body
+		} else {
+			c.Body = get(row, "comment")
+		}
+		if created := get(row, "date"); created != "" {
+			if t, ok := parseGDPRDate(created); ok {
+				c.Source.CreatedUTC = float64(t.Unix())
+			}
+		}
+
+		things = append(things, c)
+	}
+
+	return things, nil
+}
+
+// pushshiftEntry is the subset of a Pushshift comment/submission record
+// shreddit needs to reconstruct a Comment.
+type pushshiftEntry struct {
+	ID         string  `json:"id"`
+	Permalink  string  `json:"permalink"`
+	Subreddit  string  `json:"subreddit"`
+	Body       string  `json:"body"`
+	CreatedUTC float64 `json:"created_utc"`
+	Score      int64   `json:"score"`
+}
+
+// parsePushshiftArchive accepts either a bare JSON array of entries or the
+// `{"data": [...]}` envelope Pushshift's API itself returns.
+func parsePushshiftArchive(data []byte) ([]Thing, error) {
+	var entries []pushshiftEntry
+
+	var envelope struct {
+		Data []pushshiftEntry `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Data != nil {
+		entries = envelope.Data
+	} else if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	things := make([]Thing, 0, len(entries))
+	for _, e := range entries {
+		if e.ID == "" {
+			continue
+		}
+		things = append(things, &Comment{
+			ID:        e.ID,
+			Body:      e.Body,
+			Permalink: e.Permalink,
+			Subreddit: e.Subreddit,
+			Source: Source{
+				Score:      e.Score,
+				CreatedUTC: e.CreatedUTC,
+			},
+		})
+	}
+
+	return things, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import "testing"
+
+func TestLooksLikeJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"array", `[{"id":"1"}]`, true},
+		{"object", `{"data":[]}`, true},
+		{"csv", "id,permalink,subreddit,date\n1,/r/x,x,2020-01-01", false},
+		{"empty", "", false},
+		{"leading whitespace json", "  \n[1,2,3]", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := looksLikeJSON([]byte(c.data)); got != c.want {
+				t.Errorf("looksLikeJSON(%q) = %v, want %v", c.data, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseGDPRDate(t *testing.T) {
+	got, ok := parseGDPRDate("2015-03-01 10:23:45 UTC")
+	if !ok {
+		t.Fatal("expected the GDPR export's space-separated UTC date format to parse")
+	}
+	if want := int64(1425205425); got.Unix() != want {
+		t.Errorf("parsed unix time = %d, want %d", got.Unix(), want)
+	}
+
+	if _, ok := parseGDPRDate("not a date"); ok {
+		t.Error("garbage input should not parse")
+	}
+}
+
+func TestParseCSVArchive(t *testing.T) {
+	csvData := "id,permalink,subreddit,body,date\n" +
+		"abc123,/r/golang/comments/abc123,golang,hello world,2015-03-01 10:23:45 UTC\n" +
+		",/r/golang/comments/skip,golang,no id so skip,2015-03-01 10:23:45 UTC\n"
+
+	things, err := parseCSVArchive([]byte(csvData))
+	if err != nil {
+		t.Fatalf("parseCSVArchive returned error: %v", err)
+	}
+	if len(things) != 1 {
+		t.Fatalf("got %d things, want 1", len(things))
+	}
+
+	c, ok := things[0].(*Comment)
+	if !ok {
+		t.Fatalf("thing is %T, want *Comment", things[0])
+	}
+	if c.ID != "abc123" || c.Subreddit != "golang" || c.Body != "hello world" {
+		t.Errorf("unexpected comment: %+v", c)
+	}
+	if c.Created().IsZero() || c.Created().Year() != 2015 {
+		t.Errorf("created = %v, want year 2015", c.Created())
+	}
+}
+
+func TestParseCSVArchivePostsFallBackToCommentColumn(t *testing.T) {
+	csvData := "id,permalink,subreddit,comment,date\n" +
+		"xyz789,/r/golang/comments/xyz789,golang,a self post,2018-06-15 00:00:00 UTC\n"
+
+	things, err := parseCSVArchive([]byte(csvData))
+	if err != nil {
+		t.Fatalf("parseCSVArchive returned error: %v", err)
+	}
+	if len(things) != 1 {
+		t.Fatalf("got %d things, want 1", len(things))
+	}
+	c := things[0].(*Comment)
+	if c.Body != "a self post" {
+		t.Errorf("body = %q, want fallback to the comment column", c.Body)
+	}
+}
+
+func TestParsePushshiftArchiveBareArray(t *testing.T) {
+	data := `[{"id":"1","subreddit":"golang","body":"hi","score":5,"created_utc":1600000000}]`
+
+	things, err := parsePushshiftArchive([]byte(data))
+	if err != nil {
+		t.Fatalf("parsePushshiftArchive returned error: %v", err)
+	}
+	if len(things) != 1 {
+		t.Fatalf("got %d things, want 1", len(things))
+	}
+	c := things[0].(*Comment)
+	if c.ID != "1" || c.Score != 5 || c.CreatedUTC != 1600000000 {
+		t.Errorf("unexpected comment: %+v", c)
+	}
+}
+
+func TestParsePushshiftArchiveEnvelope(t *testing.T) {
+	data := `{"data":[{"id":"2","subreddit":"golang","body":"hi again","score":1,"created_utc":1600000001}]}`
+
+	things, err := parsePushshiftArchive([]byte(data))
+	if err != nil {
+		t.Fatalf("parsePushshiftArchive returned error: %v", err)
+	}
+	if len(things) != 1 {
+		t.Fatalf("got %d things, want 1", len(things))
+	}
+	c := things[0].(*Comment)
+	if c.ID != "2" || c.Score != 1 {
+		t.Errorf("unexpected comment: %+v", c)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRefreshSkew is how long before an access token's reported expiry we
+// proactively refresh it, so a request is never built with a token that
+// expires mid-flight.
+const tokenRefreshSkew = 60 * time.Second
+
+// authorizeTimeout bounds how long we wait for the user to complete the
+// browser authorization step before giving up.
+const authorizeTimeout = 5 * time.Minute
+
+// ErrOAuthRevoked is returned when Reddit responds with 401/403 even after
+// a forced refresh, meaning the refresh token itself is no longer valid and
+// the installed-app authorization flow must be run again.
+var ErrOAuthRevoked = errors.New("reddit: oauth token revoked or expired")
+
+// AuthenticatedClient wraps a redditClient with OAuth state: it performs the
+// "installed app" authorization-code handshake once, caches the resulting
+// refresh token on disk, and transparently refreshes the access token as it
+// nears expiry so callers never have to think about auth.
+type AuthenticatedClient struct {
+	rc     *redditClient
+	config *Config
+
+	mu           sync.Mutex
+	refreshToken string
+	accessToken  string
+	expiresAt    time.Time
+}
+
+// NewAuthenticatedClient authenticates against Reddit. If a cached refresh
+// token exists at config.TokenCachePath it's used directly; otherwise this
+// runs the one-time browser handshake and persists the resulting refresh
+// token for subsequent runs.
+func NewAuthenticatedClient(ctx context.Context, rc *redditClient, config *Config) (*AuthenticatedClient, error) {
+	a := &AuthenticatedClient{rc: rc, config: config}
+
+	if token, err := loadRefreshToken(config.TokenCachePath); err == nil {
+		a.refreshToken = token
+		if err := a.refresh(ctx); err == nil {
+			return a, nil
+		}
+		// Fall through to a fresh handshake if the cached token no longer works.
+	}
+
+	code, redirectURI, err := authorizeInBrowser(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authorize: %v", err)
+	}
+
+	if err := a.exchangeAuthorizationCode(ctx, code, redirectURI); err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %v", err)
+	}
+
+	return a, nil
+}
+
+// authorizeInBrowser runs the installed-app handshake: it starts a local
+// redirect listener, prints the authorize URL for the user to open, and
+// waits for Reddit to redirect back with a code.
+func authorizeInBrowser(ctx context.Context, config *Config) (code string, redirectURI string, err error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start local redirect listener: %v", err)
+	}
+	redirectURI = fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		if errMsg := r.URL.Query().Get("error"); errMsg != "" {
+			fmt.Fprintln(w, "Authorization denied, you may close this tab.")
+			errCh <- fmt.Errorf("authorization denied: %s", errMsg)
+			return
+		}
+		fmt.Fprintln(w, "Authorization complete, you may close this tab.")
+		codeCh <- r.URL.Query().Get("code")
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Printf("Open the following URL in a browser to authorize shreddit:\n%s\n", buildAuthorizeURL(config, redirectURI))
+
+	select {
+	case code := <-codeCh:
+		return code, redirectURI, nil
+	case err := <-errCh:
+		return "", "", err
+	case <-time.After(authorizeTimeout):
+		return "", "", fmt.Errorf("timed out waiting for authorization")
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	}
+}
+
+func buildAuthorizeURL(config *Config, redirectURI string) string {
+	v := url.Values{}
+	v.Set("client_id", config.ClientID)
+	v.Set("response_type", "code")
+	v.Set("state", "shreddit")
+	v.Set("redirect_uri", redirectURI)
+	v.Set("duration", "permanent")
+	v.Set("scope", "edit history identity privatemessages save")
+	return "https://www.reddit.com/api/v1/authorize?" + v.Encode()
+}
+
+func (a *AuthenticatedClient) exchangeAuthorizationCode(ctx context.Context, code, redirectURI string) error {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	return a.requestToken(ctx, form)
+}
+
+func (a *AuthenticatedClient) refresh(ctx context.Context) error {
+	a.mu.Lock()
+	refreshToken := a.refreshToken
+	a.mu.Unlock()
+
+	if refreshToken == "" {
+		return fmt.Errorf("no refresh token available")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return a.requestToken(ctx, form)
+}
+
+func (a *AuthenticatedClient) requestToken(ctx context.Context, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://www.reddit.com/api/v1/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(a.config.ClientID, a.config.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", a.config.UserAgent)
+
+	resp, err := a.rc.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res AccessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("unable to decode access token response: %v", err)
+	}
+	if res.Error != "" {
+		return fmt.Errorf("error in the response: %s", res.ErrorDesc)
+	}
+
+	a.mu.Lock()
+	a.accessToken = res.AccessToken
+	a.expiresAt = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	if res.RefreshToken != "" {
+		a.refreshToken = res.RefreshToken
+	}
+	a.mu.Unlock()
+
+	if err := a.persistRefreshToken(); err != nil {
+		slog.Warn("failed to persist refresh token", "error", err)
+	}
+
+	return nil
+}
+
+func (a *AuthenticatedClient) persistRefreshToken() error {
+	if a.config.TokenCachePath == "" {
+		return nil
+	}
+	a.mu.Lock()
+	refreshToken := a.refreshToken
+	a.mu.Unlock()
+	if refreshToken == "" {
+		return nil
+	}
+	return os.WriteFile(a.config.TokenCachePath, []byte(refreshToken), 0600)
+}
+
+func loadRefreshToken(path string) (string, error) {
+	if path == "" {
+		return "", os.ErrNotExist
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", os.ErrNotExist
+	}
+	return token, nil
+}
+
+// token returns a non-expired access token, refreshing first if the cached
+// one is missing or within tokenRefreshSkew of expiring.
+func (a *AuthenticatedClient) token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	accessToken := a.accessToken
+	expiresAt := a.expiresAt
+	a.mu.Unlock()
+
+	if accessToken == "" || time.Until(expiresAt) < tokenRefreshSkew {
+		if err := a.refresh(ctx); err != nil {
+			return "", err
+		}
+		a.mu.Lock()
+		accessToken = a.accessToken
+		a.mu.Unlock()
+	}
+
+	return accessToken, nil
+}
+
+// Do attaches a valid access token to req and sends it through the
+// underlying rate-limited client. A 401/403 triggers a single forced
+// refresh and retry before giving up with ErrOAuthRevoked.
+func (a *AuthenticatedClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	accessToken, err := a.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := a.rc.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	if err := a.refresh(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOAuthRevoked, err)
+	}
+
+	accessToken, err = a.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+
+	resp, err = a.rc.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, ErrOAuthRevoked
+	}
+
+	return resp, nil
+}
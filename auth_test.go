@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirectTransport rewrites every request's scheme/host to target's, so
+// code that posts to reddit.com's hardcoded OAuth endpoints can be pointed
+// at an httptest.Server instead.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestAuthenticatedClient(server *httptest.Server) *AuthenticatedClient {
+	target, _ := url.Parse(server.URL)
+	httpClient := &http.Client{Transport: &redirectTransport{target: target}}
+	rc := newRedditClient(httpClient, 0)
+	return &AuthenticatedClient{
+		rc: rc,
+		config: &Config{
+			ClientID:     "client-id",
+			ClientSecret: "client-secret",
+			UserAgent:    "shreddit-test",
+		},
+		refreshToken: "refresh-token",
+	}
+}
+
+func TestAuthenticatedClientDoRefreshesAndRetriesOn401(t *testing.T) {
+	var endpointCalls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"new-token","expires_in":3600,"token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/api/some-endpoint", func(w http.ResponseWriter, r *http.Request) {
+		endpointCalls++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "x=1" {
+			t.Errorf("retried request body = %q, want %q", body, "x=1")
+		}
+		if r.Header.Get("Authorization") != "Bearer new-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	a := newTestAuthenticatedClient(server)
+	a.accessToken = "old-token"
+	a.expiresAt = time.Now().Add(time.Hour)
+
+	req, err := http.NewRequestWithContext(context.Background(), "POST", "https://oauth.reddit.com/api/some-endpoint", strings.NewReader("x=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := a.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("final status = %d, want 200", resp.StatusCode)
+	}
+	if endpointCalls != 2 {
+		t.Errorf("endpoint was called %d times, want 2 (initial 401 then retry)", endpointCalls)
+	}
+}
+
+func TestAuthenticatedClientTokenRefreshesWithinSkew(t *testing.T) {
+	var refreshed bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/access_token", func(w http.ResponseWriter, r *http.Request) {
+		refreshed = true
+		w.Write([]byte(`{"access_token":"refreshed-token","expires_in":3600,"token_type":"bearer"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	a := newTestAuthenticatedClient(server)
+	a.accessToken = "about-to-expire"
+	a.expiresAt = time.Now().Add(tokenRefreshSkew / 2)
+
+	token, err := a.token(context.Background())
+	if err != nil {
+		t.Fatalf("token returned error: %v", err)
+	}
+	if !refreshed {
+		t.Error("expected a token within tokenRefreshSkew of expiry to trigger a refresh")
+	}
+	if token != "refreshed-token" {
+		t.Errorf("token = %q, want %q", token, "refreshed-token")
+	}
+}
+
+func TestAuthenticatedClientDoReturnsErrOAuthRevoked(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"still-bad-token","expires_in":3600,"token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/api/some-endpoint", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	a := newTestAuthenticatedClient(server)
+	a.accessToken = "old-token"
+	a.expiresAt = time.Now().Add(time.Hour)
+
+	req, err := http.NewRequestWithContext(context.Background(), "POST", "https://oauth.reddit.com/api/some-endpoint", strings.NewReader("x=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = a.Do(context.Background(), req)
+	if !errors.Is(err, ErrOAuthRevoked) {
+		t.Fatalf("Do error = %v, want ErrOAuthRevoked", err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitBuffer is the number of remaining requests under which the
+// client will pause and wait out the rest of Reddit's rate-limit window
+// rather than risk a 429.
+const defaultRateLimitBuffer = 50
+
+// retryBackoff is the fixed backoff schedule used between retryable
+// failures (network errors, 5xx, 429). The final entry is the last wait
+// before giving up.
+var retryBackoff = []time.Duration{
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+}
+
+// RateLimitSnapshot is the most recently observed state of Reddit's
+// per-OAuth-client rate limit, as reported in the x-ratelimit-* headers.
+type RateLimitSnapshot struct {
+	Remaining float64
+	Used      float64
+	Reset     time.Duration
+	Observed  time.Time
+}
+
+func (s RateLimitSnapshot) String() string {
+	if s.Observed.IsZero() {
+		return "rate limit: unknown (no requests made yet)"
+	}
+	return fmt.Sprintf("rate limit: %.0f remaining, %.0f used, resets in %s", s.Remaining, s.Used, s.Reset)
+}
+
+// redditClient wraps an *http.Client so every call site shares the same
+// rate-limit bookkeeping and retry behavior instead of making raw requests.
+type redditClient struct {
+	http   *http.Client
+	buffer float64
+
+	mu        sync.Mutex
+	rateLimit RateLimitSnapshot
+}
+
+func newRedditClient(httpClient *http.Client, buffer int) *redditClient {
+	if buffer <= 0 {
+		buffer = defaultRateLimitBuffer
+	}
+	return &redditClient{
+		http:   httpClient,
+		buffer: float64(buffer),
+	}
+}
+
+// RateLimitSnapshot returns the last observed rate-limit state so callers
+// (e.g. main) can log it between operations.
+func (rc *redditClient) RateLimitSnapshot() RateLimitSnapshot {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.rateLimit
+}
+
+// Do sends req, retrying on network errors, 5xx, and 429 responses using
+// the fixed backoff schedule, and pacing requests so the client never
+// knowingly runs the account out of its rate-limit window. ctx governs both
+// the rate-limit wait and the retry backoff, so a cancelled worker doesn't
+// block shutdown sitting in a sleep.
+func (rc *redditClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := rc.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		resp, err = rc.http.Do(req)
+		if err == nil {
+			rc.recordRateLimit(resp)
+		}
+
+		if !shouldRetry(resp, err) || attempt >= len(retryBackoff) {
+			break
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, bodyErr
+			}
+			req.Body = body
+		}
+
+		if err := sleep(ctx, retryBackoff[attempt]); err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, err
+}
+
+// sleep waits out d, or returns ctx.Err() early if ctx is cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// waitForRateLimit sleeps until the rate-limit window resets if the last
+// observed response said we're within the configured buffer of tripping a
+// 429.
+func (rc *redditClient) waitForRateLimit(ctx context.Context) error {
+	rc.mu.Lock()
+	snapshot := rc.rateLimit
+	rc.mu.Unlock()
+
+	if snapshot.Observed.IsZero() || snapshot.Remaining > rc.buffer {
+		return nil
+	}
+
+	wait := snapshot.Reset - time.Since(snapshot.Observed)
+	if wait > 0 {
+		slog.Info("approaching rate limit, pausing", "remaining", snapshot.Remaining, "wait", wait)
+		return sleep(ctx, wait)
+	}
+	return nil
+}
+
+func (rc *redditClient) recordRateLimit(resp *http.Response) {
+	remaining, okRemaining := parseRateLimitFloat(resp.Header.Get("x-ratelimit-remaining"))
+	used, okUsed := parseRateLimitFloat(resp.Header.Get("x-ratelimit-used"))
+	reset, okReset := parseRateLimitFloat(resp.Header.Get("x-ratelimit-reset"))
+	if !okRemaining && !okUsed && !okReset {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if okRemaining {
+		rc.rateLimit.Remaining = remaining
+	}
+	if okUsed {
+		rc.rateLimit.Used = used
+	}
+	if okReset {
+		rc.rateLimit.Reset = time.Duration(reset) * time.Second
+	}
+	rc.rateLimit.Observed = time.Now()
+}
+
+func parseRateLimitFloat(value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
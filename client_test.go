@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShouldRetry(t *testing.T) {
+	cases := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"network error", nil, context.DeadlineExceeded, true},
+		{"too many requests", &http.Response{StatusCode: http.StatusTooManyRequests}, nil, true},
+		{"server error", &http.Response{StatusCode: http.StatusInternalServerError}, nil, true},
+		{"success", &http.Response{StatusCode: http.StatusOK}, nil, false},
+		{"client error", &http.Response{StatusCode: http.StatusBadRequest}, nil, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := shouldRetry(c.resp, c.err); got != c.want {
+				t.Errorf("shouldRetry(%+v, %v) = %v, want %v", c.resp, c.err, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseRateLimitFloat(t *testing.T) {
+	if _, ok := parseRateLimitFloat(""); ok {
+		t.Error("empty string should not parse")
+	}
+	if _, ok := parseRateLimitFloat("not-a-number"); ok {
+		t.Error("non-numeric string should not parse")
+	}
+	got, ok := parseRateLimitFloat("42.5")
+	if !ok || got != 42.5 {
+		t.Errorf("parseRateLimitFloat(42.5) = %v, %v, want 42.5, true", got, ok)
+	}
+}
+
+func TestSleepRespectsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleep(ctx, time.Second); err == nil {
+		t.Error("expected sleep to return early with ctx already cancelled")
+	}
+}
+
+func TestRedditClientDoRetriesOnServerError(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rc := newRedditClient(server.Client(), 0)
+	oldBackoff := retryBackoff
+	retryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond}
+	defer func() { retryBackoff = oldBackoff }()
+
+	req, err := http.NewRequestWithContext(context.Background(), "POST", server.URL, strings.NewReader("x=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := rc.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("final status = %d, want 200", resp.StatusCode)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
@@ -1,29 +1,35 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 )
 
+// defaultConcurrency is how many workers process the edit/delete pipeline
+// at once when Config.Concurrency isn't set.
+const defaultConcurrency = 4
+
 type AccessTokenResponse struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int    `json:"expires_in"`
-	Scope       string `json:"scope"`
-	TokenType   string `json:"token_type"`
-	Error       string `json:"error,omitempty"`
-	ErrorDesc   string `json:"error_description,omitempty"`
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ErrorDesc    string `json:"error_description,omitempty"`
 }
 
 type Config struct {
 	Username           string
-	Password           string
 	ClientID           string
 	ClientSecret       string
 	UserAgent          string
@@ -33,11 +39,22 @@ type Config struct {
 	MaxScore           int
 	ReplacementComment string
 	DryRun             bool
+	RateLimitBuffer    int
+	TokenCachePath     string
+	SkipSubmissionIDs  []string
+	SkipMessageIDs     []string
+	SkipSaved          bool
+	SkipSavedIDs       []string
+	ProcessSubmissions bool
+	ProcessSaved       bool
+	ProcessMessages    bool
+	ArchivePath        string
+	Concurrency        int
+	ReportPath         string
 }
 
 type RawConfig struct {
 	Username           string   `json:"username"`
-	Password           string   `json:"password"`
 	ClientID           string   `json:"ClientID"`
 	ClientSecret       string   `json:"ClientSecret"`
 	UserAgent          string   `json:"UserAgent"`
@@ -47,35 +64,18 @@ type RawConfig struct {
 	MaxScore           int      `json:"MaxScore"`
 	ReplacementComment string   `json:"ReplacementComment"`
 	DryRun             bool     `json:"DryRun"`
-}
-
-type Comment struct {
-	ID        string
-	Body      string
-	Permalink string
-	Subreddit string
-	Source    Source
-}
-
-type Source struct {
-	Score      int64
-	CreatedUTC float64
-	CanGild    bool
-	Date       time.Time
-}
-
-type ResponseData struct {
-	Children []Child
-	After    string
-	Before   string
-}
-
-type Child struct {
-	Data Comment
-}
-
-type Response struct {
-	Data ResponseData
+	RateLimitBuffer    int      `json:"RateLimitBuffer"`
+	TokenCachePath     string   `json:"TokenCachePath"`
+	SkipSubmissionIDs  []string `json:"SkipSubmissionIDs"`
+	SkipMessageIDs     []string `json:"SkipMessageIDs"`
+	SkipSaved          bool     `json:"SkipSaved"`
+	SkipSavedIDs       []string `json:"SkipSavedIDs"`
+	ProcessSubmissions bool     `json:"ProcessSubmissions"`
+	ProcessSaved       bool     `json:"ProcessSaved"`
+	ProcessMessages    bool     `json:"ProcessMessages"`
+	ArchivePath        string   `json:"ArchivePath"`
+	Concurrency        int      `json:"Concurrency"`
+	ReportPath         string   `json:"ReportPath"`
 }
 
 // EnvVar or Json Value
@@ -105,7 +105,6 @@ func configLoader(filePath string) (*Config, error) {
 
 	config := &Config{
 		Username:           rawConfig.Username,
-		Password:           getEnvOrDefault("REDDIT_PASSWORD", rawConfig.Password),
 		ClientID:           rawConfig.ClientID,
 		ClientSecret:       getEnvOrDefault("REDDIT_CLIENT_SECRET", rawConfig.ClientSecret),
 		UserAgent:          rawConfig.UserAgent,
@@ -115,288 +114,128 @@ func configLoader(filePath string) (*Config, error) {
 		MaxScore:           rawConfig.MaxScore,
 		ReplacementComment: rawConfig.ReplacementComment,
 		DryRun:             rawConfig.DryRun,
+		RateLimitBuffer:    rawConfig.RateLimitBuffer,
+		TokenCachePath:     rawConfig.TokenCachePath,
+		SkipSubmissionIDs:  rawConfig.SkipSubmissionIDs,
+		SkipMessageIDs:     rawConfig.SkipMessageIDs,
+		SkipSaved:          rawConfig.SkipSaved,
+		SkipSavedIDs:       rawConfig.SkipSavedIDs,
+		ProcessSubmissions: rawConfig.ProcessSubmissions,
+		ProcessSaved:       rawConfig.ProcessSaved,
+		ProcessMessages:    rawConfig.ProcessMessages,
+		ArchivePath:        rawConfig.ArchivePath,
+		Concurrency:        rawConfig.Concurrency,
+		ReportPath:         rawConfig.ReportPath,
 	}
 
 	return config, nil
 }
 
-func (c *Comment) Created() time.Time {
-	return time.Unix(int64(c.Source.CreatedUTC), 0)
-}
-
-func (c *Comment) Fullname() string {
-	return "t1_" + c.ID
-}
-
-func (c *Comment) ShouldSkip(config *Config) bool {
-	for _, id := range config.SkipCommentIDs {
-		if id == c.ID {
-			fmt.Printf("Skipping due to `skip_comment_ids` filter\n")
-			return true
-		}
-	}
-	for _, subreddit := range config.SkipSubreddits {
-		fmt.Printf("Subreddit: %v\n", subreddit)
-		if subreddit == c.Subreddit {
-			fmt.Printf("Skipping due to `skip_subreddits` filter\n")
-			return true
-		}
-	}
-	if c.Created().After(config.Before) {
-		fmt.Printf("Skipping due to `before` filter (%s)\n", config.Before)
-		return true
-	}
-	if c.Source.Score > int64(config.MaxScore) {
-		fmt.Printf("Skipping due to `max_score` filter (%d)\n", config.MaxScore)
-		return true
-	}
-	return false
-}
-
-func LoadConfig(filename string) (*Config, error) {
-	// Open File
-	file, err := os.Open(filename)
-	if err != nil {
-		fmt.Printf("Failed to open config file: %v", err)
-		return nil, err
-	}
-	defer file.Close()
-
-	// Read File
-	byteValue, err := ioutil.ReadAll(file)
-	if err != nil {
-		fmt.Printf("Failed to read config file: %v", err)
-		return nil, err
-	}
-
-	var config Config
-	// Unmarshal JSON into config struct
-	if err := json.Unmarshal(byteValue, &config); err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal config into struct: %v", err)
-	}
-
-	// Override sensitive fields with environment variables
-	if password := os.Getenv("REDDIT_PASSWORD"); password != "" {
-		config.Password = password
-	} else {
-		return nil, fmt.Errorf("Reddit password not set")
-	}
-
-	if clientSecret := os.Getenv("REDDIT_CLIENT_SECRET"); clientSecret != "" {
-		config.ClientSecret = clientSecret
-	} else {
-		return nil, fmt.Errorf("Reddit Client Secret not set")
-	}
-
-	// Handle yearsBack from environment variable
-	yearsBackStr := os.Getenv("REDDIT_YEARS_BACK")
-	yearsBack, err := strconv.Atoi(yearsBackStr)
-	if err != nil {
-		if yearsBackStr != "" {
-			fmt.Printf("Invalid value for REDDIT_YEARS_BACK: %v", err)
-			return nil, err
-		}
-		yearsBack = 11 // Default to 11 if not set or invalid
-	}
-
-	config.Before = time.Now().AddDate(-yearsBack, 0, 0)
-
-	// Handle DryRun
-	config.DryRun = os.Getenv("REDDIT_DRY_RUN") == "true"
-
-	return &config, nil
-}
-
-func (c *Comment) Delete(client *http.Client, accessToken string, config *Config) {
-
-	if c.ShouldSkip(config) || config.DryRun {
-		fmt.Println("dryrun set or item set to be skipped, skipping deletion.")
-		return
-	}
-
-	fmt.Println("Deleting...")
-	data := url.Values{}
-	data.Set("id", c.Fullname())
-
-	req, err := http.NewRequest("POST", "https://oauth.reddit.com/api/del", strings.NewReader(data.Encode()))
-	if err != nil {
-		fmt.Printf("Failed to send delete request: %v\n", err)
-		return
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("User-Agent", config.UserAgent)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Failed to delete comment: %v\n", err)
-		return
+// enabledKinds returns every listing kind this run should walk, based on
+// which optional categories the config turns on. Comments are always shredded.
+func enabledKinds(config *Config) []ListingKind {
+	kinds := []ListingKind{KindComments}
+	if config.ProcessSubmissions {
+		kinds = append(kinds, KindSubmitted)
+	}
+	if config.ProcessSaved {
+		kinds = append(kinds, KindSaved)
+	}
+	if config.ProcessMessages {
+		kinds = append(kinds, KindInbox)
+	}
+	return kinds
+}
+
+// processListing fans a single listing's Things out across a pool of
+// workers that run Edit+Delete in parallel, stopping promptly if ctx is
+// cancelled instead of draining the channel. Every candidate, skip, and
+// rate-limit observation flows through reporter, so dry runs and real runs
+// produce the same receipt.
+func processListing(ctx context.Context, rc *redditClient, doer httpDoer, client *AuthenticatedClient, config *Config, kind ListingKind, reporter *Reporter) {
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	things := List(ctx, doer, config, kind)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case thing, ok := <-things:
+					if !ok {
+						return
+					}
+					if editable, ok := thing.(Editable); ok {
+						editable.Edit(ctx, client, config, reporter)
+					}
+					thing.Delete(ctx, client, config, reporter)
+
+					slog.Debug(rc.RateLimitSnapshot().String())
+				}
+			}
+		}()
 	}
-	defer resp.Body.Close()
+	wg.Wait()
 }
 
-func (c *Comment) Edit(client *http.Client, accessToken string, config *Config) {
-	if c.ShouldSkip(config) || config.DryRun {
-		return
-	}
-
-	fmt.Println("Editing...")
-
-	data := url.Values{}
-	data.Set("thing_id", c.Fullname())
-	data.Set("text", config.ReplacementComment)
-
-	req, err := http.NewRequest("POST", "https://oauth.reddit.com/api/editusertext?raw_json=1", strings.NewReader(data.Encode()))
-	if err != nil {
-		fmt.Printf("Failed to create request: %v\n", err)
-		return
-	}
+func main() {
+	archivePath := flag.String("archive", "", "path or URL to a GDPR export CSV or Pushshift-style JSON dump of older comments, used to get around Reddit's ~1000-item listing cap")
+	logFormat := flag.String("log-format", "text", "log output format: text or json")
+	flag.Parse()
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("User-Agent", config.UserAgent)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	resp, err := client.Do(req)
+	// Load config
+	config, err := configLoader("config.json")
 	if err != nil {
-		fmt.Printf("Failed to edit comment: %v\n", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	var res map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		fmt.Printf("Failed to decode response: %v\n", err)
-		return
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
 	}
 
-	if _, ok := res["jquery"]; ok {
-		fmt.Printf("Edited successfully.\n")
-	} else {
-		fmt.Printf("Failed to edit: %v\n", res)
-	}
-}
-
-func List(client *http.Client, config *Config) <-chan Comment {
-	out := make(chan Comment)
+	reporter := NewReporter(*logFormat, config.ReportPath)
+	slog.SetDefault(reporter.Logger())
+	defer reporter.Finish()
 
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
-		defer close(out)
-		fmt.Println("Fetching comments...")
-		var lastSeen string
-
-		for {
-			queryParams := ""
-			if lastSeen != "" {
-				queryParams = "?after=" + lastSeen
-			}
-
-			uri := fmt.Sprintf("https://reddit.com/user/%s/comments.json%s", config.Username, queryParams)
-
-			req, err := http.NewRequest("GET", uri, nil)
-			if err != nil {
-				fmt.Printf("Failed to create request: %v", err)
-				return
-			}
-
-			req.Header.Set("User-Agent", config.UserAgent)
-
-			resp, err := client.Do(req)
-			if err != nil {
-				fmt.Printf("Failed to fetch comments: %v", err)
-				return
-			}
-			defer resp.Body.Close()
-
-			var res Response
-			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-				fmt.Printf("Failed to decode response: %v", err)
-				return
-			}
-
-			for _, child := range res.Data.Children {
-				out <- child.Data
-			}
-
-			if len(res.Data.Children) == 0 || res.Data.After == "" {
-				break
-			}
-
-			lastSeen = res.Data.After
-		}
+		<-sigCh
+		slog.Info("received interrupt, finishing in-flight requests and stopping")
+		cancel()
 	}()
 
-	return out
-}
-
-func newAccessToken(config *Config) (string, error) {
-	// Prepare form data
-	form := url.Values{}
-	form.Add("grant_type", "password")
-	form.Add("username", config.Username)
-	form.Add("password", config.Password)
-
-	// Prepare request
-	req, err := http.NewRequest("POST", "https://www.reddit.com/api/v1/access_token", strings.NewReader(form.Encode()))
-	if err != nil {
-		return "", err
-	}
-
-	req.SetBasicAuth(config.ClientID, config.ClientSecret)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", config.UserAgent)
-
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	// Try to decode response as JSON
-	var res AccessTokenResponse
-	if err := json.Unmarshal(body, &res); err != nil {
-		// Log the response body for further inspection
-		return "", fmt.Errorf("unable to decode response: %v\nResponse body: %s", err, body)
+	if *archivePath != "" {
+		config.ArchivePath = *archivePath
 	}
 
-	// Check for errors in the response
-	if res.Error != "" {
-		return "", fmt.Errorf("error in the response: %s", res.ErrorDesc)
-	}
+	rc := newRedditClient(&http.Client{}, config.RateLimitBuffer)
 
-	return res.AccessToken, nil
-}
-
-func main() {
-	// Load config
-	config, err := configLoader("config.json")
+	// Authenticate, reusing a cached refresh token when one is available.
+	client, err := NewAuthenticatedClient(ctx, rc, config)
 	if err != nil {
-		fmt.Errorf("Error loading config: %v\n", err)
+		slog.Error("failed to authenticate", "error", err)
+		os.Exit(1)
 	}
 
-	// Get access token
-	accessToken, err := newAccessToken(config)
-	if err != nil {
-		fmt.Errorf("Failed to obtain access token: %v\n", err)
-	}
+	for _, kind := range enabledKinds(config) {
+		if ctx.Err() != nil {
+			break
+		}
 
-	client := &http.Client{}
-	// List and process comments
-	for comment := range List(client, config) {
-		// Two-step approach to cleaning: edit first, then delete
-		comment.Edit(client, accessToken, config)
-		comment.Delete(client, accessToken, config)
+		var doer httpDoer = rc
+		if kind.requiresAuth() {
+			doer = client
+		}
 
-		// Sleep to avoid throttling
-		//fmt.Print("Sleeping 15 seconds\n")
-		//time.Sleep(15 * time.Second)
+		processListing(ctx, rc, doer, client, config, kind, reporter)
 	}
 }
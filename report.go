@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// bodyTruncateLen caps how much of a comment/post body is logged per
+// candidate, so a dry-run over a large history doesn't flood the log with
+// full post text.
+const bodyTruncateLen = 200
+
+// Reporter is the single place Edit/Delete/ShouldSkip funnel through to
+// produce shreddit's structured output: a slog record per skip and per
+// edit/delete candidate (dry-run or real), plus a run summary at the end.
+// Dry-run and real runs share this same path, so a dry run shows exactly
+// what a real run would have done, and a real run leaves the same receipt.
+type Reporter struct {
+	logger     *slog.Logger
+	reportPath string
+
+	mu          sync.Mutex
+	touched     int
+	bySubreddit map[string]int
+	skipReasons map[string]int
+	oldest      time.Time
+	newest      time.Time
+	accounted   map[string]bool
+}
+
+// Logger returns the underlying slog.Logger so the rest of the program can
+// log through the same handler (and therefore the same --log-format) as the
+// reporter itself.
+func (r *Reporter) Logger() *slog.Logger {
+	return r.logger
+}
+
+// NewReporter builds a Reporter whose logger writes to stdout in the given
+// format ("json" or anything else for text), and whose Finish also writes a
+// JSON summary to reportPath if one is configured.
+func NewReporter(logFormat, reportPath string) *Reporter {
+	var handler slog.Handler
+	if logFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, nil)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, nil)
+	}
+
+	return &Reporter{
+		logger:      slog.New(handler),
+		reportPath:  reportPath,
+		bySubreddit: make(map[string]int),
+		skipReasons: make(map[string]int),
+		accounted:   make(map[string]bool),
+	}
+}
+
+// firstTime reports whether fullname hasn't been counted towards the
+// summary yet, and marks it counted either way. Edit and Delete each run
+// ShouldSkip/report a candidate for the same Thing in turn, so without this
+// every processed or skipped item would be counted twice.
+func (r *Reporter) firstTime(fullname string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.accounted[fullname] {
+		return false
+	}
+	r.accounted[fullname] = true
+	return true
+}
+
+// Skip records why a Thing was excluded, counted towards the final skip
+// reasons histogram the first time it's reported for a given fullname.
+func (r *Reporter) Skip(kind, fullname, subreddit, reason string) {
+	if r.firstTime(fullname) {
+		r.mu.Lock()
+		r.skipReasons[reason]++
+		r.mu.Unlock()
+	}
+
+	r.logger.Info("skipping",
+		"kind", kind,
+		"fullname", fullname,
+		"subreddit", subreddit,
+		"reason", reason,
+	)
+}
+
+// Candidate records one Thing that Edit or Delete is about to act on (or,
+// in dry-run mode, would act on), showing exactly what's being destroyed:
+// its subreddit, permalink, score, creation date, current body, and the
+// replacement text if any. Summary counts only reflect the first time a
+// given fullname is reported, even though Edit and Delete each call this.
+func (r *Reporter) Candidate(action, kind, fullname, subreddit, permalink string, score int64, created time.Time, currentBody, proposedText string, dryRun bool) {
+	if r.firstTime(fullname) {
+		r.mu.Lock()
+		r.touched++
+		r.bySubreddit[subreddit]++
+		if r.oldest.IsZero() || created.Before(r.oldest) {
+			r.oldest = created
+		}
+		if r.newest.IsZero() || created.After(r.newest) {
+			r.newest = created
+		}
+		r.mu.Unlock()
+	}
+
+	attrs := []any{
+		"action", action,
+		"kind", kind,
+		"fullname", fullname,
+		"subreddit", subreddit,
+		"permalink", permalink,
+		"score", score,
+		"created", created.Format(time.RFC3339),
+		"current_body", truncate(currentBody),
+	}
+	if proposedText != "" {
+		attrs = append(attrs, "proposed_text", truncate(proposedText))
+	}
+
+	message := action
+	if dryRun {
+		message = "dry-run: would " + action
+	}
+	r.logger.Info(message, attrs...)
+}
+
+func truncate(body string) string {
+	if len(body) <= bodyTruncateLen {
+		return body
+	}
+	return body[:bodyTruncateLen] + "..."
+}
+
+// reportSummary is the JSON shape written to Config.ReportPath.
+type reportSummary struct {
+	Touched     int            `json:"touched"`
+	BySubreddit map[string]int `json:"by_subreddit"`
+	SkipReasons map[string]int `json:"skip_reasons"`
+	Oldest      string         `json:"oldest,omitempty"`
+	Newest      string         `json:"newest,omitempty"`
+}
+
+// Finish logs the run summary (counts by subreddit, oldest/newest touched
+// item, skip reasons histogram) and, if a report path is configured, writes
+// the same summary to it as JSON.
+func (r *Reporter) Finish() {
+	r.mu.Lock()
+	summary := reportSummary{
+		Touched:     r.touched,
+		BySubreddit: r.bySubreddit,
+		SkipReasons: r.skipReasons,
+	}
+	if !r.oldest.IsZero() {
+		summary.Oldest = r.oldest.Format(time.RFC3339)
+	}
+	if !r.newest.IsZero() {
+		summary.Newest = r.newest.Format(time.RFC3339)
+	}
+	r.mu.Unlock()
+
+	r.logger.Info("run summary",
+		"touched", summary.Touched,
+		"by_subreddit", summary.BySubreddit,
+		"skip_reasons", summary.SkipReasons,
+		"oldest", summary.Oldest,
+		"newest", summary.Newest,
+	)
+
+	if r.reportPath == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		r.logger.Error("failed to marshal report", "error", err)
+		return
+	}
+	if err := os.WriteFile(r.reportPath, data, 0644); err != nil {
+		r.logger.Error("failed to write report file", "path", r.reportPath, "error", err)
+		return
+	}
+	fmt.Printf("Wrote run report to %s\n", r.reportPath)
+}
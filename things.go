@@ -0,0 +1,598 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ListingKind identifies which Reddit listing to walk. Each kind maps to a
+// distinct endpoint and, for the mutating ones, a distinct delete action.
+type ListingKind string
+
+const (
+	KindComments  ListingKind = "comments"
+	KindSubmitted ListingKind = "submitted"
+	KindSaved     ListingKind = "saved"
+	KindInbox     ListingKind = "inbox"
+)
+
+// requiresAuth reports whether a listing kind can only be fetched with an
+// OAuth bearer token, as opposed to the public, unauthenticated JSON feeds.
+func (k ListingKind) requiresAuth() bool {
+	return k == KindSaved || k == KindInbox
+}
+
+// listingPath returns the endpoint path for a given kind. Saved/inbox are
+// account-scoped and require the oauth.reddit.com host; the rest are public
+// and served from reddit.com.
+func (k ListingKind) listingPath(config *Config) (host, path string) {
+	switch k {
+	case KindInbox:
+		return "oauth.reddit.com", "/message/inbox.json"
+	case KindSaved:
+		return "oauth.reddit.com", fmt.Sprintf("/user/%s/saved.json", config.Username)
+	case KindSubmitted:
+		return "reddit.com", fmt.Sprintf("/user/%s/submitted.json", config.Username)
+	default:
+		return "reddit.com", fmt.Sprintf("/user/%s/comments.json", config.Username)
+	}
+}
+
+// Thing is anything Reddit listings can return that shreddit knows how to
+// remove: comments, submissions, saved items, and private messages.
+type Thing interface {
+	Fullname() string
+	Kind() string
+	ShouldSkip(config *Config, reporter *Reporter) bool
+	Delete(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter)
+}
+
+// Editable is implemented by Things that support the edit-then-delete
+// pattern shreddit uses to scrub content before removing it.
+type Editable interface {
+	Thing
+	Edit(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter)
+}
+
+// httpDoer is satisfied by both redditClient and AuthenticatedClient, so
+// List can be pointed at either depending on whether the listing kind needs
+// an OAuth token.
+type httpDoer interface {
+	Do(ctx context.Context, req *http.Request) (*http.Response, error)
+}
+
+// Source holds the fields Reddit's listing JSON reports flat on each
+// thing's data object; it's embedded (not nested under a "source" key) so
+// those fields unmarshal directly from that flat JSON.
+type Source struct {
+	Score      int64     `json:"score"`
+	CreatedUTC float64   `json:"created_utc"`
+	CanGild    bool      `json:"can_gild"`
+	Date       time.Time `json:"-"`
+}
+
+type Comment struct {
+	ID        string `json:"id"`
+	Body      string `json:"body"`
+	Permalink string `json:"permalink"`
+	Subreddit string `json:"subreddit"`
+	Source
+}
+
+func (c *Comment) Created() time.Time {
+	return time.Unix(int64(c.Source.CreatedUTC), 0)
+}
+
+func (c *Comment) Fullname() string {
+	return "t1_" + c.ID
+}
+
+func (c *Comment) Kind() string {
+	return "t1"
+}
+
+func (c *Comment) ShouldSkip(config *Config, reporter *Reporter) bool {
+	for _, id := range config.SkipCommentIDs {
+		if id == c.ID {
+			reporter.Skip(c.Kind(), c.Fullname(), c.Subreddit, "skip_comment_ids")
+			return true
+		}
+	}
+	for _, subreddit := range config.SkipSubreddits {
+		if subreddit == c.Subreddit {
+			reporter.Skip(c.Kind(), c.Fullname(), c.Subreddit, "skip_subreddits")
+			return true
+		}
+	}
+	if c.Created().After(config.Before) {
+		reporter.Skip(c.Kind(), c.Fullname(), c.Subreddit, "before")
+		return true
+	}
+	if c.Source.Score > int64(config.MaxScore) {
+		reporter.Skip(c.Kind(), c.Fullname(), c.Subreddit, "max_score")
+		return true
+	}
+	return false
+}
+
+func (c *Comment) Delete(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter) {
+	if c.ShouldSkip(config, reporter) {
+		return
+	}
+
+	reporter.Candidate("delete", c.Kind(), c.Fullname(), c.Subreddit, c.Permalink, c.Source.Score, c.Created(), c.Body, "", config.DryRun)
+	if config.DryRun {
+		return
+	}
+
+	data := url.Values{}
+	data.Set("id", c.Fullname())
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth.reddit.com/api/del", strings.NewReader(data.Encode()))
+	if err != nil {
+		slog.Error("failed to build delete request", "fullname", c.Fullname(), "error", err)
+		return
+	}
+
+	req.Header.Set("User-Agent", config.UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		slog.Error("failed to delete comment", "fullname", c.Fullname(), "error", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+func (c *Comment) Edit(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter) {
+	if c.ShouldSkip(config, reporter) {
+		return
+	}
+
+	reporter.Candidate("edit", c.Kind(), c.Fullname(), c.Subreddit, c.Permalink, c.Source.Score, c.Created(), c.Body, config.ReplacementComment, config.DryRun)
+	if config.DryRun {
+		return
+	}
+
+	data := url.Values{}
+	data.Set("thing_id", c.Fullname())
+	data.Set("text", config.ReplacementComment)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth.reddit.com/api/editusertext?raw_json=1", strings.NewReader(data.Encode()))
+	if err != nil {
+		slog.Error("failed to build edit request", "fullname", c.Fullname(), "error", err)
+		return
+	}
+
+	req.Header.Set("User-Agent", config.UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		slog.Error("failed to edit comment", "fullname", c.Fullname(), "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var res map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		slog.Error("failed to decode edit response", "fullname", c.Fullname(), "error", err)
+		return
+	}
+
+	if _, ok := res["jquery"]; !ok {
+		slog.Error("edit may have failed", "fullname", c.Fullname(), "response", res)
+	}
+}
+
+// Submission is a t3 link or self post.
+type Submission struct {
+	ID        string `json:"id"`
+	Permalink string `json:"permalink"`
+	Subreddit string `json:"subreddit"`
+	IsSelf    bool   `json:"is_self"`
+	Selftext  string `json:"selftext"`
+	Source
+}
+
+func (s *Submission) Created() time.Time {
+	return time.Unix(int64(s.Source.CreatedUTC), 0)
+}
+
+func (s *Submission) Fullname() string {
+	return "t3_" + s.ID
+}
+
+func (s *Submission) Kind() string {
+	return "t3"
+}
+
+func (s *Submission) ShouldSkip(config *Config, reporter *Reporter) bool {
+	for _, id := range config.SkipSubmissionIDs {
+		if id == s.ID {
+			reporter.Skip(s.Kind(), s.Fullname(), s.Subreddit, "skip_submission_ids")
+			return true
+		}
+	}
+	for _, subreddit := range config.SkipSubreddits {
+		if subreddit == s.Subreddit {
+			reporter.Skip(s.Kind(), s.Fullname(), s.Subreddit, "skip_subreddits")
+			return true
+		}
+	}
+	if s.Created().After(config.Before) {
+		reporter.Skip(s.Kind(), s.Fullname(), s.Subreddit, "before")
+		return true
+	}
+	if s.Source.Score > int64(config.MaxScore) {
+		reporter.Skip(s.Kind(), s.Fullname(), s.Subreddit, "max_score")
+		return true
+	}
+	return false
+}
+
+// Edit only applies to self posts; link posts have no body text to scrub,
+// so they go straight to Delete.
+func (s *Submission) Edit(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter) {
+	if !s.IsSelf || s.ShouldSkip(config, reporter) {
+		return
+	}
+
+	reporter.Candidate("edit", s.Kind(), s.Fullname(), s.Subreddit, s.Permalink, s.Source.Score, s.Created(), s.Selftext, config.ReplacementComment, config.DryRun)
+	if config.DryRun {
+		return
+	}
+
+	data := url.Values{}
+	data.Set("thing_id", s.Fullname())
+	data.Set("text", config.ReplacementComment)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth.reddit.com/api/editusertext?raw_json=1", strings.NewReader(data.Encode()))
+	if err != nil {
+		slog.Error("failed to build edit request", "fullname", s.Fullname(), "error", err)
+		return
+	}
+
+	req.Header.Set("User-Agent", config.UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		slog.Error("failed to edit submission", "fullname", s.Fullname(), "error", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+func (s *Submission) Delete(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter) {
+	if s.ShouldSkip(config, reporter) {
+		return
+	}
+
+	reporter.Candidate("delete", s.Kind(), s.Fullname(), s.Subreddit, s.Permalink, s.Source.Score, s.Created(), s.Selftext, "", config.DryRun)
+	if config.DryRun {
+		return
+	}
+
+	data := url.Values{}
+	data.Set("id", s.Fullname())
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth.reddit.com/api/del", strings.NewReader(data.Encode()))
+	if err != nil {
+		slog.Error("failed to build delete request", "fullname", s.Fullname(), "error", err)
+		return
+	}
+
+	req.Header.Set("User-Agent", config.UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		slog.Error("failed to delete submission", "fullname", s.Fullname(), "error", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+// Message is a t4 private message.
+type Message struct {
+	ID   string `json:"id"`
+	Body string `json:"body"`
+	Source
+}
+
+func (m *Message) Created() time.Time {
+	return time.Unix(int64(m.Source.CreatedUTC), 0)
+}
+
+func (m *Message) Fullname() string {
+	return "t4_" + m.ID
+}
+
+func (m *Message) Kind() string {
+	return "t4"
+}
+
+func (m *Message) ShouldSkip(config *Config, reporter *Reporter) bool {
+	for _, id := range config.SkipMessageIDs {
+		if id == m.ID {
+			reporter.Skip(m.Kind(), m.Fullname(), "", "skip_message_ids")
+			return true
+		}
+	}
+	if m.Created().After(config.Before) {
+		reporter.Skip(m.Kind(), m.Fullname(), "", "before")
+		return true
+	}
+	return false
+}
+
+func (m *Message) Delete(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter) {
+	if m.ShouldSkip(config, reporter) {
+		return
+	}
+
+	reporter.Candidate("delete", m.Kind(), m.Fullname(), "", "", 0, m.Created(), m.Body, "", config.DryRun)
+	if config.DryRun {
+		return
+	}
+
+	data := url.Values{}
+	data.Set("id", m.Fullname())
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth.reddit.com/api/del_msg", strings.NewReader(data.Encode()))
+	if err != nil {
+		slog.Error("failed to build delete request", "fullname", m.Fullname(), "error", err)
+		return
+	}
+
+	req.Header.Set("User-Agent", config.UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		slog.Error("failed to delete message", "fullname", m.Fullname(), "error", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+// SavedItem is an entry from the saved listing. Saved entries can wrap
+// either a comment or a submission, but unsaving only needs the fullname,
+// so it doesn't need to know which.
+type SavedItem struct {
+	RawKind   string `json:"-"`
+	ID        string `json:"id"`
+	Subreddit string `json:"subreddit"`
+	Source
+}
+
+func (s *SavedItem) Created() time.Time {
+	return time.Unix(int64(s.Source.CreatedUTC), 0)
+}
+
+func (s *SavedItem) Fullname() string {
+	return s.RawKind + "_" + s.ID
+}
+
+func (s *SavedItem) Kind() string {
+	return s.RawKind
+}
+
+func (s *SavedItem) ShouldSkip(config *Config, reporter *Reporter) bool {
+	if config.SkipSaved {
+		reporter.Skip(s.Kind(), s.Fullname(), s.Subreddit, "skip_saved")
+		return true
+	}
+	for _, id := range config.SkipSavedIDs {
+		if id == s.ID {
+			reporter.Skip(s.Kind(), s.Fullname(), s.Subreddit, "skip_saved_ids")
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SavedItem) Delete(ctx context.Context, client *AuthenticatedClient, config *Config, reporter *Reporter) {
+	if s.ShouldSkip(config, reporter) {
+		return
+	}
+
+	reporter.Candidate("unsave", s.Kind(), s.Fullname(), s.Subreddit, "", s.Source.Score, s.Created(), "", "", config.DryRun)
+	if config.DryRun {
+		return
+	}
+
+	data := url.Values{}
+	data.Set("id", s.Fullname())
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth.reddit.com/api/unsave", strings.NewReader(data.Encode()))
+	if err != nil {
+		slog.Error("failed to build unsave request", "fullname", s.Fullname(), "error", err)
+		return
+	}
+
+	req.Header.Set("User-Agent", config.UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(ctx, req)
+	if err != nil {
+		slog.Error("failed to unsave item", "fullname", s.Fullname(), "error", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+// rawChild and rawListing mirror Reddit's generic Listing shape, where each
+// child carries its own "kind" ("t1", "t3", "t4", ...) alongside its data so
+// mixed-kind listings (saved) can be decoded without knowing the kind up
+// front.
+type rawChild struct {
+	Kind string
+	Data json.RawMessage
+}
+
+type rawListing struct {
+	Data struct {
+		Children []rawChild
+		After    string
+		Before   string
+	}
+}
+
+// decodeThing turns a single listing child into the Thing it represents.
+func decodeThing(child rawChild) (Thing, error) {
+	switch child.Kind {
+	case "t1":
+		var c Comment
+		if err := json.Unmarshal(child.Data, &c); err != nil {
+			return nil, fmt.Errorf("failed to decode comment: %v", err)
+		}
+		return &c, nil
+	case "t3":
+		var s Submission
+		if err := json.Unmarshal(child.Data, &s); err != nil {
+			return nil, fmt.Errorf("failed to decode submission: %v", err)
+		}
+		return &s, nil
+	case "t4":
+		var m Message
+		if err := json.Unmarshal(child.Data, &m); err != nil {
+			return nil, fmt.Errorf("failed to decode message: %v", err)
+		}
+		return &m, nil
+	default:
+		return nil, fmt.Errorf("unrecognized thing kind: %q", child.Kind)
+	}
+}
+
+// decodeSavedThing decodes a saved-listing child into a SavedItem, which
+// only cares about the fullname needed to unsave it.
+func decodeSavedThing(child rawChild) (Thing, error) {
+	var s SavedItem
+	if err := json.Unmarshal(child.Data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode saved item: %v", err)
+	}
+	s.RawKind = child.Kind
+	return &s, nil
+}
+
+// List walks a Reddit listing of the given kind, paginating with "after"
+// until exhausted, and yields each entry as a Thing. Saved and inbox
+// listings require doer to be an *AuthenticatedClient; the rest work with
+// the plain rate-limited client since they're public. It stops early,
+// without emitting a partial page, if ctx is cancelled.
+func List(ctx context.Context, doer httpDoer, config *Config, kind ListingKind) <-chan Thing {
+	out := make(chan Thing)
+
+	emit := func(thing Thing) bool {
+		select {
+		case out <- thing:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(out)
+
+		seen := make(map[string]bool)
+
+		// Reddit's listing endpoints only ever return the newest ~1000
+		// items, so for comments we first drain an external archive (GDPR
+		// export or Pushshift dump) covering older history, then let the
+		// live listing below fill in anything since the archive was taken.
+		if kind == KindComments && config.ArchivePath != "" {
+			archived, err := loadArchive(ctx, config.ArchivePath)
+			if err != nil {
+				slog.Error("failed to load archive", "path", config.ArchivePath, "error", err)
+			}
+			for _, thing := range archived {
+				if seen[thing.Fullname()] {
+					continue
+				}
+				seen[thing.Fullname()] = true
+				if !emit(thing) {
+					return
+				}
+			}
+		}
+
+		slog.Info("fetching listing", "kind", kind)
+		var lastSeen string
+		host, path := kind.listingPath(config)
+
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+
+			queryParams := ""
+			if lastSeen != "" {
+				queryParams = "?after=" + lastSeen
+			}
+
+			uri := fmt.Sprintf("https://%s%s%s", host, path, queryParams)
+
+			req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+			if err != nil {
+				slog.Error("failed to create request", "error", err)
+				return
+			}
+
+			req.Header.Set("User-Agent", config.UserAgent)
+
+			resp, err := doer.Do(ctx, req)
+			if err != nil {
+				slog.Error("failed to fetch listing", "kind", kind, "error", err)
+				return
+			}
+			defer resp.Body.Close()
+
+			var res rawListing
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+				slog.Error("failed to decode listing response", "error", err)
+				return
+			}
+
+			for _, child := range res.Data.Children {
+				var (
+					thing Thing
+					err   error
+				)
+				if kind == KindSaved {
+					thing, err = decodeSavedThing(child)
+				} else {
+					thing, err = decodeThing(child)
+				}
+				if err != nil {
+					slog.Error("skipping unrecognized listing entry", "error", err)
+					continue
+				}
+				if seen[thing.Fullname()] {
+					continue
+				}
+				seen[thing.Fullname()] = true
+				if !emit(thing) {
+					return
+				}
+			}
+
+			if len(res.Data.Children) == 0 || res.Data.After == "" {
+				break
+			}
+
+			lastSeen = res.Data.After
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testReporter() *Reporter {
+	return NewReporter("text", "")
+}
+
+func TestDecodeThingComment(t *testing.T) {
+	child := rawChild{
+		Kind: "t1",
+		Data: json.RawMessage(`{"id":"abc","body":"hi","permalink":"/r/golang/comments/abc","subreddit":"golang","score":5,"created_utc":1600000000}`),
+	}
+
+	thing, err := decodeThing(child)
+	if err != nil {
+		t.Fatalf("decodeThing returned error: %v", err)
+	}
+	c, ok := thing.(*Comment)
+	if !ok {
+		t.Fatalf("thing is %T, want *Comment", thing)
+	}
+	if c.ID != "abc" || c.Body != "hi" || c.Subreddit != "golang" {
+		t.Errorf("unexpected comment: %+v", c)
+	}
+	if c.Score != 5 {
+		t.Errorf("score = %d, want 5", c.Score)
+	}
+	if c.Created().Unix() != 1600000000 {
+		t.Errorf("created = %v, want unix 1600000000", c.Created())
+	}
+	if c.Fullname() != "t1_abc" {
+		t.Errorf("fullname = %q, want t1_abc", c.Fullname())
+	}
+}
+
+func TestDecodeThingSubmission(t *testing.T) {
+	child := rawChild{
+		Kind: "t3",
+		Data: json.RawMessage(`{"id":"def","permalink":"/r/golang/comments/def","subreddit":"golang","is_self":true,"selftext":"body text","score":3,"created_utc":1600000001}`),
+	}
+
+	thing, err := decodeThing(child)
+	if err != nil {
+		t.Fatalf("decodeThing returned error: %v", err)
+	}
+	s, ok := thing.(*Submission)
+	if !ok {
+		t.Fatalf("thing is %T, want *Submission", thing)
+	}
+	if !s.IsSelf || s.Selftext != "body text" || s.Score != 3 {
+		t.Errorf("unexpected submission: %+v", s)
+	}
+	if s.Fullname() != "t3_def" {
+		t.Errorf("fullname = %q, want t3_def", s.Fullname())
+	}
+}
+
+func TestDecodeThingMessage(t *testing.T) {
+	child := rawChild{
+		Kind: "t4",
+		Data: json.RawMessage(`{"id":"ghi","body":"a message","created_utc":1600000002}`),
+	}
+
+	thing, err := decodeThing(child)
+	if err != nil {
+		t.Fatalf("decodeThing returned error: %v", err)
+	}
+	m, ok := thing.(*Message)
+	if !ok {
+		t.Fatalf("thing is %T, want *Message", thing)
+	}
+	if m.Body != "a message" || m.Created().Unix() != 1600000002 {
+		t.Errorf("unexpected message: %+v", m)
+	}
+	if m.Fullname() != "t4_ghi" {
+		t.Errorf("fullname = %q, want t4_ghi", m.Fullname())
+	}
+}
+
+func TestDecodeThingUnrecognizedKind(t *testing.T) {
+	child := rawChild{Kind: "t5", Data: json.RawMessage(`{}`)}
+	if _, err := decodeThing(child); err == nil {
+		t.Error("expected an error for an unrecognized thing kind")
+	}
+}
+
+func TestDecodeSavedThing(t *testing.T) {
+	child := rawChild{
+		Kind: "t3",
+		Data: json.RawMessage(`{"id":"jkl","subreddit":"golang","score":1,"created_utc":1600000003}`),
+	}
+
+	thing, err := decodeSavedThing(child)
+	if err != nil {
+		t.Fatalf("decodeSavedThing returned error: %v", err)
+	}
+	s, ok := thing.(*SavedItem)
+	if !ok {
+		t.Fatalf("thing is %T, want *SavedItem", thing)
+	}
+	if s.Fullname() != "t3_jkl" || s.Kind() != "t3" {
+		t.Errorf("unexpected saved item: %+v", s)
+	}
+}
+
+func TestCommentShouldSkip(t *testing.T) {
+	before := time.Unix(1600000000, 0)
+	base := func() *Comment {
+		return &Comment{
+			ID:        "abc",
+			Subreddit: "golang",
+			Source:    Source{Score: 1, CreatedUTC: float64(before.Add(-time.Hour).Unix())},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		config *Config
+		mutate func(*Comment)
+		want   bool
+	}{
+		{
+			name:   "skip_comment_ids",
+			config: &Config{Before: before, SkipCommentIDs: []string{"abc"}},
+			want:   true,
+		},
+		{
+			name:   "skip_subreddits",
+			config: &Config{Before: before, SkipSubreddits: []string{"golang"}},
+			want:   true,
+		},
+		{
+			name:   "before",
+			config: &Config{Before: before.Add(-2 * time.Hour)},
+			want:   true,
+		},
+		{
+			name:   "max_score",
+			config: &Config{Before: before, MaxScore: 0},
+			want:   true,
+		},
+		{
+			name:   "kept",
+			config: &Config{Before: before, MaxScore: 10},
+			want:   false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			comment := base()
+			if c.mutate != nil {
+				c.mutate(comment)
+			}
+			if got := comment.ShouldSkip(c.config, testReporter()); got != c.want {
+				t.Errorf("ShouldSkip() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSubmissionShouldSkip(t *testing.T) {
+	before := time.Unix(1600000000, 0)
+	base := func() *Submission {
+		return &Submission{
+			ID:        "def",
+			Subreddit: "golang",
+			Source:    Source{Score: 1, CreatedUTC: float64(before.Add(-time.Hour).Unix())},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		config *Config
+		want   bool
+	}{
+		{"skip_submission_ids", &Config{Before: before, SkipSubmissionIDs: []string{"def"}}, true},
+		{"skip_subreddits", &Config{Before: before, SkipSubreddits: []string{"golang"}}, true},
+		{"before", &Config{Before: before.Add(-2 * time.Hour)}, true},
+		{"max_score", &Config{Before: before, MaxScore: 0}, true},
+		{"kept", &Config{Before: before, MaxScore: 10}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := base().ShouldSkip(c.config, testReporter()); got != c.want {
+				t.Errorf("ShouldSkip() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMessageShouldSkip(t *testing.T) {
+	before := time.Unix(1600000000, 0)
+	base := func() *Message {
+		return &Message{
+			ID:     "ghi",
+			Source: Source{CreatedUTC: float64(before.Add(-time.Hour).Unix())},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		config *Config
+		want   bool
+	}{
+		{"skip_message_ids", &Config{Before: before, SkipMessageIDs: []string{"ghi"}}, true},
+		{"before", &Config{Before: before.Add(-2 * time.Hour)}, true},
+		{"kept", &Config{Before: before}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := base().ShouldSkip(c.config, testReporter()); got != c.want {
+				t.Errorf("ShouldSkip() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSavedItemShouldSkip(t *testing.T) {
+	base := func() *SavedItem {
+		return &SavedItem{RawKind: "t1", ID: "jkl", Subreddit: "golang"}
+	}
+
+	cases := []struct {
+		name   string
+		config *Config
+		want   bool
+	}{
+		{"skip_saved", &Config{SkipSaved: true}, true},
+		{"skip_saved_ids", &Config{SkipSavedIDs: []string{"jkl"}}, true},
+		{"kept", &Config{}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := base().ShouldSkip(c.config, testReporter()); got != c.want {
+				t.Errorf("ShouldSkip() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}